@@ -1,28 +1,38 @@
 package main
 
 import (
-    "encoding/csv"
+    "context"
     "encoding/json"
     "flag"
     "fmt"
     "io/ioutil"
     "log"
+    "net"
     "net/http"
     "os"
+    "os/signal"
+    "strconv"
     "strings"
+    "sync"
+    "sync/atomic"
     "time"
 )
 
-var (
-    client *http.Client
-    seen   map[string]bool
-)
+var client *http.Client
 
 type StatusResponse struct {
     Result struct {
         NodeInfo struct {
-            Network string `json:"network"`
+            Network    string `json:"network"`
+            ListenAddr string `json:"listen_addr"`
         } `json:"node_info"`
+        SyncInfo struct {
+            LatestBlockHeight string `json:"latest_block_height"`
+            CatchingUp        bool   `json:"catching_up"`
+        } `json:"sync_info"`
+        ValidatorInfo struct {
+            VotingPower string `json:"voting_power"`
+        } `json:"validator_info"`
     } `json:"result"`
 }
 
@@ -41,8 +51,85 @@ type NetInfoResponse struct {
     } `json:"result"`
 }
 
-func queryNode(url string, path string) ([]byte, error) {
-    resp, err := client.Get(url + path)
+// hostLimiter is a token-bucket rate limiter scoped to a single host, so one
+// slow or chatty peer can't starve requests to the rest of the network.
+type hostLimiter struct {
+    mu     sync.Mutex
+    tokens float64
+    rps    float64
+    last   time.Time
+}
+
+func newHostLimiter(rps float64) *hostLimiter {
+    return &hostLimiter{tokens: rps, rps: rps, last: time.Now()}
+}
+
+func (l *hostLimiter) wait(ctx context.Context) error {
+    for {
+        l.mu.Lock()
+        now := time.Now()
+        l.tokens += now.Sub(l.last).Seconds() * l.rps
+        burst := l.rps
+        if burst < 1 {
+            burst = 1
+        }
+        if l.tokens > burst {
+            l.tokens = burst
+        }
+        l.last = now
+        if l.tokens >= 1 {
+            l.tokens--
+            l.mu.Unlock()
+            return nil
+        }
+        l.mu.Unlock()
+
+        select {
+        case <-ctx.Done():
+            return ctx.Err()
+        case <-time.After(25 * time.Millisecond):
+        }
+    }
+}
+
+// limiterPool hands out one hostLimiter per host, created lazily.
+type limiterPool struct {
+    mu       sync.Mutex
+    limiters map[string]*hostLimiter
+    rps      float64
+}
+
+func newLimiterPool(rps float64) *limiterPool {
+    return &limiterPool{limiters: make(map[string]*hostLimiter), rps: rps}
+}
+
+func (p *limiterPool) forHost(host string) *hostLimiter {
+    p.mu.Lock()
+    defer p.mu.Unlock()
+    l, ok := p.limiters[host]
+    if !ok {
+        l = newHostLimiter(p.rps)
+        p.limiters[host] = l
+    }
+    return l
+}
+
+// hostFromURL strips the scheme and port from an "http://host:port" crawl
+// target, for recording just the bare host/IP in the peer db.
+func hostFromURL(url string) string {
+    host := strings.TrimPrefix(strings.TrimPrefix(url, "http://"), "https://")
+    if idx := strings.LastIndex(host, ":"); idx != -1 {
+        host = host[:idx]
+    }
+    return host
+}
+
+func queryNode(ctx context.Context, url string, path string) ([]byte, error) {
+    req, err := http.NewRequestWithContext(ctx, http.MethodGet, url+path, nil)
+    if err != nil {
+        return nil, err
+    }
+    resp, err := client.Do(req)
     if err != nil {
         return nil, err
     }
@@ -51,38 +138,253 @@ func queryNode(url string, path string) ([]byte, error) {
     return ioutil.ReadAll(resp.Body)
 }
 
-func checkAndAddNode(network, url string, output [][]string) [][]string {
+// crawler holds the shared state for an in-progress BFS crawl: the dedup set,
+// the result accumulator, and the per-host rate limiters workers pull from.
+// It replaces the old recursive checkAndAddNode, which blew the stack on
+// large networks and serialized every request behind a single HTTP client.
+type crawler struct {
+    network     string
+    nodeTimeout time.Duration
+    limiters    *limiterPool
+    sink        OutputSink
+    metrics     *Metrics
+    geoIP       *geoIPLookup
+    store       *PeerStore
+    pex         *PEXClient
+
+    healthSamples  int
+    staleThreshold int64
+    maxHeight      int64
+    graph          *Graph
+
+    seenMu sync.Mutex
+    seen   map[string]bool
+
+    recordsMu sync.Mutex
+    records   []PeerRecord
+
+    jobs chan string
+    wg   sync.WaitGroup
+}
+
+func newCrawler(network string, nodeTimeout time.Duration, rps float64, queueSize int, sink OutputSink, metrics *Metrics, geoIP *geoIPLookup, store *PeerStore, pex *PEXClient, healthSamples int, staleThreshold int64, graph *Graph) *crawler {
+    return &crawler{
+        network:        network,
+        nodeTimeout:    nodeTimeout,
+        limiters:       newLimiterPool(rps),
+        sink:           sink,
+        metrics:        metrics,
+        geoIP:          geoIP,
+        store:          store,
+        pex:            pex,
+        healthSamples:  healthSamples,
+        staleThreshold: staleThreshold,
+        graph:          graph,
+        seen:           make(map[string]bool),
+        jobs:           make(chan string, queueSize),
+    }
+}
+
+// markSeen returns true the first time it's called for a given URL.
+func (c *crawler) markSeen(url string) bool {
+    c.seenMu.Lock()
+    defer c.seenMu.Unlock()
+    if c.seen[url] {
+        return false
+    }
+    c.seen[url] = true
+    return true
+}
+
+// enqueue queues url for crawling, unless it's already been seen. The send
+// is non-blocking: if c.jobs is momentarily full (a discovery burst can fill
+// it while every worker is itself trying to enqueue), the send is handed to
+// a background goroutine instead of blocking the calling worker, so a full
+// queue can never wedge every worker against the same channel at once.
+func (c *crawler) enqueue(ctx context.Context, url string) {
+    if !c.markSeen(url) {
+        return
+    }
+    c.wg.Add(1)
+    select {
+    case c.jobs <- url:
+    default:
+        go func() {
+            select {
+            case c.jobs <- url:
+            case <-ctx.Done():
+                c.wg.Done()
+            }
+        }()
+    }
+}
+
+// record hands a discovered peer off for output. Metrics and the peer db
+// don't depend on Stale, so they're updated immediately regardless of
+// stale-threshold tracking — only the sink write needs the corrected Stale,
+// and when stale-threshold tracking is enabled that can only be judged once
+// the crawl's final max height is known, so the record is buffered and
+// finalize writes it to the sink once the crawl finishes.
+func (c *crawler) record(p PeerRecord) {
+    c.recordMetrics(p)
+
+    if c.staleThreshold >= 0 && c.healthSamples > 0 {
+        c.recordsMu.Lock()
+        c.records = append(c.records, p)
+        c.recordsMu.Unlock()
+        return
+    }
+    c.writeSink(p)
+}
+
+// recordMetrics updates the Prometheus gauges and peer db for a discovered
+// peer. These are independent of Stale, so unlike the sink write they never
+// need to wait for finalize.
+func (c *crawler) recordMetrics(p PeerRecord) {
+    if c.metrics != nil {
+        c.metrics.RecordPeer(p.Network, p.NodeInfo.Version, p.NodeInfo.Moniker)
+    }
+    if c.store != nil {
+        if err := c.store.UpsertPeer(p, time.Now()); err != nil {
+            fmt.Printf("Error recording peer %s in peer db: %v\n", p.IP, err)
+        }
+    }
+}
+
+func (c *crawler) writeSink(p PeerRecord) {
+    if err := c.sink.Write(p); err != nil {
+        fmt.Printf("Error writing peer %s to output sink: %v\n", p.IP, err)
+    }
+}
+
+// finalize recomputes Stale for every buffered record against the crawl's
+// final max height, then writes each one to the sink. Staleness computed
+// during the crawl (against the running max at discovery time) would make
+// the flag order- and timing-dependent — in particular, seeds probed first
+// could never be flagged stale regardless of how far behind they end up.
+func (c *crawler) finalize() {
+    c.recordsMu.Lock()
+    records := c.records
+    c.records = nil
+    c.recordsMu.Unlock()
+
+    finalMax := atomic.LoadInt64(&c.maxHeight)
+    for _, p := range records {
+        if p.Health.LatestBlockHeight > 0 {
+            p.Health.Stale = finalMax-p.Health.LatestBlockHeight > c.staleThreshold
+        }
+        c.writeSink(p)
+    }
+}
+
+// noteQueryError records a failed /status or /net_info query in the metrics
+// sink, distinguishing a timed-out context from any other failure.
+func (c *crawler) noteQueryError(ctx context.Context, err error) {
+    if c.metrics == nil {
+        return
+    }
+    if ctx.Err() == context.DeadlineExceeded || err == context.DeadlineExceeded {
+        c.metrics.IncQueryTimeout()
+        return
+    }
+    c.metrics.IncQueryFailure()
+}
+
+// runWorkers starts n BFS workers draining c.jobs, stopping once the queue is
+// fully drained (c.wg reaches zero) or ctx is cancelled.
+func (c *crawler) runWorkers(ctx context.Context, n int) {
+    drained := make(chan struct{})
+    go func() {
+        c.wg.Wait()
+        close(drained)
+    }()
+
+    var workers sync.WaitGroup
+    for i := 0; i < n; i++ {
+        workers.Add(1)
+        go func() {
+            defer workers.Done()
+            for {
+                select {
+                case <-ctx.Done():
+                    return
+                case <-drained:
+                    return
+                case url := <-c.jobs:
+                    c.checkAndAddNode(ctx, url)
+                    c.wg.Done()
+                }
+            }
+        }()
+    }
+
+    // A worker that exits via ctx.Done() leaves its in-flight job unconsumed,
+    // so on cancellation c.wg may never reach zero and drained never closes.
+    // Wait for whichever comes first so a SIGINT actually aborts the crawl
+    // instead of hanging here forever.
+    select {
+    case <-drained:
+    case <-ctx.Done():
+    }
+    workers.Wait()
+}
+
+// checkAndAddNode queries a single peer's /status and /net_info and, if it
+// matches the target network, records each newly discovered peer from its
+// /net_info and enqueues it as a new job rather than recursing into it
+// directly. The queried node itself is only recorded in the peer db.
+func (c *crawler) checkAndAddNode(ctx context.Context, url string) {
+    nodeCtx, cancel := context.WithTimeout(ctx, c.nodeTimeout)
+    defer cancel()
+
+    if err := c.limiters.forHost(url).wait(nodeCtx); err != nil {
+        fmt.Printf("Rate limiter wait aborted for %s: %v\n", url, err)
+        return
+    }
+
     fmt.Printf("Querying /status for URL: %s\n", url)
-    body, err := queryNode(url, "/status")
+    body, err := queryNode(nodeCtx, url, "/status")
     if err != nil {
         fmt.Printf("Error querying /status for %s: %v\n", url, err)
-        return output
+        c.noteQueryError(nodeCtx, err)
+        return
     }
 
     var status StatusResponse
     if err := json.Unmarshal(body, &status); err != nil {
         fmt.Printf("Error decoding status response for %s: %v\n", url, err)
-        return output
+        c.noteQueryError(nodeCtx, err)
+        return
+    }
+
+    if status.Result.NodeInfo.Network != c.network {
+        fmt.Printf("Network mismatch for %s, expected %s, got %s\n", url, c.network, status.Result.NodeInfo.Network)
+        return
     }
+    fmt.Printf("Network match confirmed for %s: %s\n", url, c.network)
 
-    if status.Result.NodeInfo.Network != network {
-        fmt.Printf("Network mismatch for %s, expected %s, got %s\n", url, network, status.Result.NodeInfo.Network)
-        return output
-    } else {
-        fmt.Printf("Network match confirmed for %s: %s\n", url, network)
+    if c.store != nil {
+        if err := c.store.TouchSuccess(url, hostFromURL(url), time.Now()); err != nil {
+            fmt.Printf("Error recording successful query for %s in peer db: %v\n", url, err)
+        }
     }
 
     fmt.Printf("Querying /net_info for URL: %s\n", url)
-    body, err = queryNode(url, "/net_info")
+    body, err = queryNode(nodeCtx, url, "/net_info")
     if err != nil {
         fmt.Printf("Error querying /net_info for %s: %v\n", url, err)
-        return output
+        c.noteQueryError(nodeCtx, err)
+        if c.pex != nil && status.Result.NodeInfo.ListenAddr != "" {
+            c.crawlViaPEX(nodeCtx, status.Result.NodeInfo.ListenAddr)
+        }
+        return
     }
 
     var netInfo NetInfoResponse
     if err := json.Unmarshal(body, &netInfo); err != nil {
         fmt.Printf("Error decoding net_info response for %s: %v\n", url, err)
-        return output
+        c.noteQueryError(nodeCtx, err)
+        return
     }
 
     fmt.Printf("Found %d peers for %s\n", len(netInfo.Result.Peers), url)
@@ -102,20 +404,98 @@ func checkAndAddNode(network, url string, output [][]string) [][]string {
         fullAddress := "http://" + ip + ":" + rpcPort
         fmt.Printf("Constructed URL for querying peer: %s, Moniker: %s, Version: %s\n", fullAddress, moniker, version)
 
-        if _, exists := seen[fullAddress]; !exists {
-            seen[fullAddress] = true
-            output = append(output, []string{ip, moniker, version}) // Assuming connection is always successful here
+        if c.graph != nil {
+            c.graph.AddEdge(url, fullAddress)
+        }
+
+        if c.markSeen(fullAddress) {
+            rec := PeerRecord{
+                IP:      ip,
+                Network: c.network,
+                NodeInfo: PeerNodeInfo{
+                    Moniker:    moniker,
+                    Version:    version,
+                    RPCAddress: fullAddress,
+                },
+            }
+            if c.geoIP != nil {
+                if geo, err := c.geoIP.lookup(ip); err != nil {
+                    fmt.Printf("GeoIP lookup failed for %s: %v\n", ip, err)
+                } else {
+                    rec.Geo = geo
+                }
+            }
+            if c.healthSamples > 0 {
+                rec.Health = c.probeNode(nodeCtx, fullAddress, c.healthSamples)
+            }
+            if c.graph != nil {
+                c.graph.SetNodeAttrs(fullAddress, graphNode{Moniker: moniker, Version: version, Country: rec.Geo.Country})
+            }
+            c.record(rec) // Assuming connection is always successful here
             fmt.Printf("Adding new peer to output: %s, %s\n", ip, moniker)
-            output = checkAndAddNode(network, fullAddress, output)
+            c.wg.Add(1)
+            select {
+            case c.jobs <- fullAddress:
+            default:
+                // The queue is momentarily full; a blocking send here would
+                // risk every worker piling up on the same channel during a
+                // discovery burst with nothing left to drain it. Hand the
+                // send to a background goroutine instead of stalling this
+                // worker.
+                go func(addr string) {
+                    select {
+                    case c.jobs <- addr:
+                    case <-ctx.Done():
+                        c.wg.Done()
+                    }
+                }(fullAddress)
+            }
         } else {
             fmt.Printf("Peer already processed: %s\n", fullAddress)
         }
     }
-    return output
 }
 
-func getNetworkIDFromSeed(seed string) (string, error) {
-    body, err := queryNode(seed, "/status")
+// crawlViaPEX asks listenAddr's p2p PEX reactor for its address book,
+// queueing each advertised peer for the usual RPC-based crawl. Many
+// validators firewall RPC but still gossip over p2p, so this is the only
+// way to discover them; we guess their RPC address from the conventional
+// CometBFT port offset (p2p+1) since PEX only advertises p2p addresses.
+func (c *crawler) crawlViaPEX(ctx context.Context, listenAddr string) {
+    fmt.Printf("Falling back to PEX for %s\n", listenAddr)
+    addrs, err := c.pex.FetchAddrs(ctx, listenAddr)
+    if err != nil {
+        fmt.Printf("PEX fetch failed for %s: %v\n", listenAddr, err)
+        return
+    }
+    fmt.Printf("PEX returned %d addresses from %s\n", len(addrs), listenAddr)
+    for _, addr := range addrs {
+        rpcAddr := guessRPCAddress(addr)
+        if rpcAddr == "" {
+            continue
+        }
+        c.enqueue(ctx, rpcAddr)
+    }
+}
+
+// guessRPCAddress turns a bare p2p "host:port" address into a best-effort
+// RPC URL, assuming the conventional CometBFT default of rpc_port ==
+// p2p_port+1 (26656/26657). There's no way to know the real RPC port from
+// PEX alone; nodes that don't follow the convention just won't resolve.
+func guessRPCAddress(p2pAddr string) string {
+    host, portStr, err := net.SplitHostPort(p2pAddr)
+    if err != nil {
+        return ""
+    }
+    port, err := strconv.Atoi(portStr)
+    if err != nil {
+        return ""
+    }
+    return fmt.Sprintf("http://%s", net.JoinHostPort(host, strconv.Itoa(port+1)))
+}
+
+func getNetworkIDFromSeed(ctx context.Context, seed string) (string, error) {
+    body, err := queryNode(ctx, seed, "/status")
     if err != nil {
         return "", err
     }
@@ -128,12 +508,12 @@ func getNetworkIDFromSeed(seed string) (string, error) {
     return status.Result.NodeInfo.Network, nil
 }
 
-func getNetworkIDFromSeeds(seedNodes []string, maxRetries int, retryInterval time.Duration) (string, error) {
+func getNetworkIDFromSeeds(ctx context.Context, seedNodes []string, maxRetries int, retryInterval time.Duration) (string, error) {
     var lastErr error
     for attempt := 1; attempt <= maxRetries; attempt++ {
         for _, seed := range seedNodes {
             fmt.Printf("Attempt %d: Trying to get network ID from seed node %s\n", attempt, seed)
-            networkID, err := getNetworkIDFromSeed(seed)
+            networkID, err := getNetworkIDFromSeed(ctx, seed)
             if err != nil {
                 fmt.Printf("Failed to get network ID from seed node %s: %v\n", seed, err)
                 lastErr = err
@@ -143,7 +523,11 @@ func getNetworkIDFromSeeds(seedNodes []string, maxRetries int, retryInterval tim
         }
         if attempt < maxRetries {
             fmt.Printf("All seed nodes failed in attempt %d. Retrying in %s...\n", attempt, retryInterval)
-            time.Sleep(retryInterval)
+            select {
+            case <-ctx.Done():
+                return "", ctx.Err()
+            case <-time.After(retryInterval):
+            }
         }
     }
     return "", fmt.Errorf("unable to get network ID from any of the provided seed nodes after %d attempts: %v", maxRetries, lastErr)
@@ -153,9 +537,37 @@ func main() {
     var seeds string
     var timeout int
     var outputFile string
+    var outputFormat string
+    var metricsAddr string
+    var geoIPDB string
+    var geoIPASNDB string
+    var dbPath string
+    var seedLookbackHours int
+    var pexEnabled bool
+    var healthSamples int
+    var staleThreshold int64
+    var graphOutput string
+    var graphFormat string
+    var workers int
+    var rps float64
+    var crawlTimeout int
     flag.StringVar(&seeds, "seeds", "", "Comma-separated list of seed nodes")
-    flag.IntVar(&timeout, "timeout", 0, "Timeout in seconds")
+    flag.IntVar(&timeout, "timeout", 0, "Per-node timeout in seconds")
     flag.StringVar(&outputFile, "output", "", "Outpute filename and path")
+    flag.StringVar(&outputFormat, "output-format", "csv", "Output format: csv, json, or ndjson")
+    flag.StringVar(&metricsAddr, "metrics-addr", "", "Address to serve Prometheus metrics on, e.g. :9100 (disabled if empty)")
+    flag.StringVar(&geoIPDB, "geoip-db", "", "Path to a MaxMind GeoLite2 City database for peer IP enrichment (disabled if empty)")
+    flag.StringVar(&geoIPASNDB, "geoip-asn-db", "", "Path to a MaxMind GeoLite2 ASN database for asn/as_org enrichment (disabled if empty; City and ASN ship as separate files)")
+    flag.StringVar(&dbPath, "db", "", "Path to a SQLite file for persistent peer tracking across crawls (disabled if empty)")
+    flag.IntVar(&seedLookbackHours, "seed-lookback", 24, "When --db is set, also seed the crawl from peers last seen within this many hours")
+    flag.BoolVar(&pexEnabled, "pex", false, "Fall back to the p2p PEX reactor for peers whose RPC /net_info is unreachable")
+    flag.IntVar(&healthSamples, "health-samples", 3, "Number of /health latency samples per discovered peer (0 disables health probing)")
+    flag.Int64Var(&staleThreshold, "stale-threshold", -1, "Flag peers whose block height lags the max observed height by more than this many blocks (negative disables)")
+    flag.StringVar(&graphOutput, "graph-output", "", "Path (without extension) to write the crawl topology graph to (disabled if empty)")
+    flag.StringVar(&graphFormat, "graph-format", "dot", "Graph output format: dot, graphml, or both")
+    flag.IntVar(&workers, "workers", 16, "Number of concurrent crawl workers")
+    flag.Float64Var(&rps, "rps", 5, "Max requests per second, per host")
+    flag.IntVar(&crawlTimeout, "crawl-timeout", 0, "Overall crawl deadline in seconds (0 = no deadline)")
     flag.Parse()
 
     if seeds == "" || timeout == 0 || outputFile == "" {
@@ -163,44 +575,123 @@ func main() {
         os.Exit(1)
     }
 
+    sink, err := newOutputSink(outputFormat, outputFile)
+    if err != nil {
+        log.Fatalf("Failed to set up output sink: %v", err)
+    }
+
+    var metrics *Metrics
+    if metricsAddr != "" {
+        metrics = NewMetrics()
+        mux := http.NewServeMux()
+        mux.Handle("/metrics", metrics)
+        go func() {
+            if err := http.ListenAndServe(metricsAddr, mux); err != nil {
+                fmt.Printf("Metrics server stopped: %v\n", err)
+            }
+        }()
+        fmt.Printf("Serving Prometheus metrics on %s/metrics\n", metricsAddr)
+    }
+
+    var geoIP *geoIPLookup
+    if geoIPDB != "" {
+        geoIP, err = newGeoIPLookup(geoIPDB, geoIPASNDB)
+        if err != nil {
+            log.Fatalf("Failed to open geoip database: %v", err)
+        }
+        defer geoIP.Close()
+    }
+
+    var store *PeerStore
+    if dbPath != "" {
+        store, err = OpenPeerStore(dbPath)
+        if err != nil {
+            log.Fatalf("Failed to open peer db: %v", err)
+        }
+        defer store.Close()
+    }
+
+    var graph *Graph
+    if graphOutput != "" {
+        graph = NewGraph()
+    }
+
     seedNodes := strings.Split(seeds, ",")
     client = &http.Client{Timeout: time.Duration(timeout) * time.Second}
-    seen = make(map[string]bool)
+
+    ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+    defer stop()
+
+    if crawlTimeout > 0 {
+        var cancel context.CancelFunc
+        ctx, cancel = context.WithTimeout(ctx, time.Duration(crawlTimeout)*time.Second)
+        defer cancel()
+    }
 
     // Define retry parameters
     maxRetries := 3
     retryInterval := 10 * time.Second
 
     // Determine network ID from the provided seed nodes with retries
-    networkID, err := getNetworkIDFromSeeds(seedNodes, maxRetries, retryInterval)
+    networkID, err := getNetworkIDFromSeeds(ctx, seedNodes, maxRetries, retryInterval)
     if err != nil {
         log.Fatalf("Failed to determine network ID: %v", err)
     }
     fmt.Printf("Using network ID: %s\n", networkID)
 
-    var output [][]string
+    var pexClient *PEXClient
+    if pexEnabled {
+        pexClient = NewPEXClient(networkID)
+    }
+
+    c := newCrawler(networkID, time.Duration(timeout)*time.Second, rps, 4096, sink, metrics, geoIP, store, pexClient, healthSamples, staleThreshold, graph)
     for _, seed := range seedNodes {
-        output = checkAndAddNode(networkID, seed, output)
+        c.enqueue(ctx, seed)
+    }
+    if store != nil {
+        recent, err := store.RecentPeers(time.Now().Add(-time.Duration(seedLookbackHours) * time.Hour))
+        if err != nil {
+            fmt.Printf("Error loading recent peers from peer db: %v\n", err)
+        }
+        for _, addr := range recent {
+            c.enqueue(ctx, addr)
+        }
+        fmt.Printf("Seeded %d additional peers from peer db (last %dh)\n", len(recent), seedLookbackHours)
     }
+    c.runWorkers(ctx, workers)
+    c.finalize()
 
-    file, err := os.Create(outputFile)
-    if err != nil {
-        log.Fatal("Cannot create file", err)
+    if ctx.Err() != nil {
+        fmt.Printf("Crawl aborted: %v\n", ctx.Err())
     }
-    defer file.Close()
 
-    writer := csv.NewWriter(file)
-    defer writer.Flush()
+    if err := sink.Close(); err != nil {
+        log.Fatalf("Error finalizing output sink: %v", err)
+    }
+    fmt.Println("Output successfully written to ", outputFile)
 
-    // Write the header row with category names
-    if err := writer.Write([]string{"ip", "moniker", "version"}); err != nil {
-        log.Fatal("Error writing header to file", err)
+    if graph != nil {
+        if err := writeGraphOutput(graph, graphOutput, graphFormat); err != nil {
+            log.Fatalf("Error writing graph output: %v", err)
+        }
     }
+}
 
-    for _, value := range output {
-        if err := writer.Write(value); err != nil {
-            log.Fatalln("Error writing record to file", err)
+// writeGraphOutput writes the crawl topology to graphOutput in the requested
+// format(s), appending .dot/.graphml when both are requested so neither
+// clobbers the other.
+func writeGraphOutput(graph *Graph, path, format string) error {
+    switch format {
+    case "dot":
+        return graph.WriteDOT(path)
+    case "graphml":
+        return graph.WriteGraphML(path)
+    case "both":
+        if err := graph.WriteDOT(path + ".dot"); err != nil {
+            return err
         }
+        return graph.WriteGraphML(path + ".graphml")
+    default:
+        return fmt.Errorf("unknown graph format %q (want dot, graphml, or both)", format)
     }
-    fmt.Println("Output successfully written to ", outputFile)
 }
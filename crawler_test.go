@@ -0,0 +1,82 @@
+package main
+
+import (
+    "context"
+    "net/http"
+    "testing"
+    "time"
+)
+
+func TestHostLimiterWaitReturnsPromptlyWhenTokensAvailable(t *testing.T) {
+    l := newHostLimiter(1000) // high rate so the test doesn't need to sleep long
+    ctx := context.Background()
+
+    start := time.Now()
+    for i := 0; i < 5; i++ {
+        if err := l.wait(ctx); err != nil {
+            t.Fatalf("wait() returned error: %v", err)
+        }
+    }
+    if time.Since(start) > time.Second {
+        t.Fatalf("wait() took too long for a high rate limiter")
+    }
+}
+
+func TestHostLimiterWaitRespectsContextCancellation(t *testing.T) {
+    l := newHostLimiter(0.001) // effectively no tokens for the test's duration
+    ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+    defer cancel()
+
+    if err := l.wait(ctx); err == nil {
+        t.Fatalf("expected wait() to return an error once the context is cancelled")
+    }
+}
+
+// TestRunWorkersStopsOnCancellation is a regression test for a hang where a
+// worker exiting via ctx.Done() left its in-flight job unconsumed, so c.wg
+// never reached zero and runWorkers blocked forever on <-drained after
+// cancellation.
+func TestRunWorkersStopsOnCancellation(t *testing.T) {
+    client = &http.Client{Timeout: time.Second}
+    sink := &jsonSink{path: t.TempDir() + "/out.json"}
+    c := newCrawler("test-net", time.Second, 1000, 4096, sink, nil, nil, nil, nil, 0, -1, nil)
+
+    // Fill the queue beyond what a single worker can drain quickly, so jobs
+    // are still pending when the context is cancelled.
+    ctx, cancel := context.WithCancel(context.Background())
+    for i := 0; i < 10; i++ {
+        c.wg.Add(1)
+        c.jobs <- "http://example.invalid:26657"
+    }
+    cancel()
+
+    done := make(chan struct{})
+    go func() {
+        c.runWorkers(ctx, 1)
+        close(done)
+    }()
+
+    select {
+    case <-done:
+    case <-time.After(2 * time.Second):
+        t.Fatalf("runWorkers did not return after context cancellation")
+    }
+}
+
+func TestGuessRPCAddress(t *testing.T) {
+    cases := []struct {
+        name, p2pAddr, want string
+    }{
+        {"conventional offset", "1.2.3.4:26656", "http://1.2.3.4:26657"},
+        {"ipv6 host", "[::1]:26656", "http://[::1]:26657"},
+        {"missing port", "1.2.3.4", ""},
+        {"non-numeric port", "1.2.3.4:abc", ""},
+    }
+    for _, c := range cases {
+        t.Run(c.name, func(t *testing.T) {
+            if got := guessRPCAddress(c.p2pAddr); got != c.want {
+                t.Errorf("guessRPCAddress(%q) = %q, want %q", c.p2pAddr, got, c.want)
+            }
+        })
+    }
+}
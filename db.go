@@ -0,0 +1,150 @@
+package main
+
+import (
+    "database/sql"
+    "fmt"
+    "strings"
+    "time"
+
+    _ "github.com/mattn/go-sqlite3"
+)
+
+// PeerStore is a SQLite-backed record of every peer ever observed across
+// crawls, so repeated runs can resume from known peers instead of starting
+// cold from --seeds every time, and so operators can answer questions like
+// "which validators went offline this week" after the fact.
+type PeerStore struct {
+    db *sql.DB
+}
+
+const peerStoreSchema = `
+CREATE TABLE IF NOT EXISTS peers (
+    rpc_address     TEXT PRIMARY KEY,
+    ip              TEXT NOT NULL,
+    first_seen      INTEGER NOT NULL,
+    last_seen       INTEGER NOT NULL,
+    last_success    INTEGER,
+    moniker_history TEXT NOT NULL DEFAULT '',
+    version_history TEXT NOT NULL DEFAULT '',
+    observations    INTEGER NOT NULL DEFAULT 0
+);`
+
+// OpenPeerStore opens the peer database in WAL mode with a busy timeout, and
+// caps the pool at a single connection. UpsertPeer and TouchSuccess are
+// check-then-act (SELECT/UPDATE followed by INSERT), and up to --workers
+// goroutines call them concurrently; without this, concurrent writers from a
+// real crawl intermittently fail with SQLITE_BUSY.
+func OpenPeerStore(path string) (*PeerStore, error) {
+    db, err := sql.Open("sqlite3", path+"?_busy_timeout=5000&_journal_mode=WAL")
+    if err != nil {
+        return nil, fmt.Errorf("opening peer db %s: %w", path, err)
+    }
+    db.SetMaxOpenConns(1)
+    if _, err := db.Exec(peerStoreSchema); err != nil {
+        db.Close()
+        return nil, fmt.Errorf("creating peers table: %w", err)
+    }
+    return &PeerStore{db: db}, nil
+}
+
+// RecentPeers returns the rpc_address of every peer observed since the given
+// time, used to seed the crawl queue alongside --seeds.
+func (s *PeerStore) RecentPeers(since time.Time) ([]string, error) {
+    rows, err := s.db.Query(`SELECT rpc_address FROM peers WHERE last_seen >= ?`, since.Unix())
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    var addrs []string
+    for rows.Next() {
+        var addr string
+        if err := rows.Scan(&addr); err != nil {
+            return nil, err
+        }
+        addrs = append(addrs, addr)
+    }
+    return addrs, rows.Err()
+}
+
+// UpsertPeer records a freshly discovered peer, appending to its moniker and
+// version history when either has changed since the last observation.
+func (s *PeerStore) UpsertPeer(rec PeerRecord, now time.Time) error {
+    var monikers, versions string
+    err := s.db.QueryRow(`SELECT moniker_history, version_history FROM peers WHERE rpc_address = ?`, rec.NodeInfo.RPCAddress).
+        Scan(&monikers, &versions)
+
+    switch {
+    case err == sql.ErrNoRows:
+        // Another writer may insert the same peer between the SELECT above
+        // and this INSERT; in that case nothing is inserted here and the
+        // call is retried, which then takes the update path.
+        res, err := s.db.Exec(
+            `INSERT OR IGNORE INTO peers (rpc_address, ip, first_seen, last_seen, moniker_history, version_history, observations)
+             VALUES (?, ?, ?, ?, ?, ?, 1)`,
+            rec.NodeInfo.RPCAddress, rec.IP, now.Unix(), now.Unix(), rec.NodeInfo.Moniker, rec.NodeInfo.Version,
+        )
+        if err != nil {
+            return err
+        }
+        if n, err := res.RowsAffected(); err != nil || n > 0 {
+            return err
+        }
+        return s.UpsertPeer(rec, now)
+    case err != nil:
+        return err
+    }
+
+    monikers = appendHistory(monikers, rec.NodeInfo.Moniker)
+    versions = appendHistory(versions, rec.NodeInfo.Version)
+
+    _, err = s.db.Exec(
+        `UPDATE peers SET last_seen = ?, moniker_history = ?, version_history = ?, observations = observations + 1
+         WHERE rpc_address = ?`,
+        now.Unix(), monikers, versions, rec.NodeInfo.RPCAddress,
+    )
+    return err
+}
+
+// TouchSuccess marks a successful /status query against rpcAddress, creating
+// the row if this is the first time the crawler has queried it directly
+// (e.g. it came from --seeds rather than another peer's /net_info).
+func (s *PeerStore) TouchSuccess(rpcAddress, ip string, now time.Time) error {
+    res, err := s.db.Exec(
+        `UPDATE peers SET last_seen = ?, last_success = ? WHERE rpc_address = ?`,
+        now.Unix(), now.Unix(), rpcAddress,
+    )
+    if err != nil {
+        return err
+    }
+    if n, err := res.RowsAffected(); err != nil {
+        return err
+    } else if n > 0 {
+        return nil
+    }
+
+    _, err = s.db.Exec(
+        `INSERT INTO peers (rpc_address, ip, first_seen, last_seen, last_success, observations)
+         VALUES (?, ?, ?, ?, ?, 0)`,
+        rpcAddress, ip, now.Unix(), now.Unix(), now.Unix(),
+    )
+    return err
+}
+
+func appendHistory(history, latest string) string {
+    if latest == "" {
+        return history
+    }
+    entries := strings.Split(history, ",")
+    if len(entries) > 0 && entries[len(entries)-1] == latest {
+        return history
+    }
+    if history == "" {
+        return latest
+    }
+    return history + "," + latest
+}
+
+func (s *PeerStore) Close() error {
+    return s.db.Close()
+}
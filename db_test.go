@@ -0,0 +1,105 @@
+package main
+
+import (
+    "path/filepath"
+    "sync"
+    "testing"
+    "time"
+)
+
+func TestAppendHistory(t *testing.T) {
+    cases := []struct {
+        history, latest, want string
+    }{
+        {"", "v1", "v1"},
+        {"v1", "v1", "v1"},
+        {"v1", "v2", "v1,v2"},
+        {"v1,v2", "v2", "v1,v2"},
+        {"v1,v2", "", "v1,v2"},
+    }
+    for _, c := range cases {
+        if got := appendHistory(c.history, c.latest); got != c.want {
+            t.Errorf("appendHistory(%q, %q) = %q, want %q", c.history, c.latest, got, c.want)
+        }
+    }
+}
+
+func TestUpsertPeerThenTouchSuccess(t *testing.T) {
+    dbPath := filepath.Join(t.TempDir(), "peers.db")
+    store, err := OpenPeerStore(dbPath)
+    if err != nil {
+        t.Fatalf("OpenPeerStore: %v", err)
+    }
+    defer store.Close()
+
+    rec := PeerRecord{
+        IP: "1.2.3.4",
+        NodeInfo: PeerNodeInfo{
+            Moniker:    "node-a",
+            Version:    "0.38.0",
+            RPCAddress: "http://1.2.3.4:26657",
+        },
+    }
+    now := time.Now()
+
+    if err := store.UpsertPeer(rec, now); err != nil {
+        t.Fatalf("UpsertPeer (insert): %v", err)
+    }
+
+    rec.NodeInfo.Version = "0.38.1"
+    if err := store.UpsertPeer(rec, now.Add(time.Minute)); err != nil {
+        t.Fatalf("UpsertPeer (update): %v", err)
+    }
+
+    if err := store.TouchSuccess(rec.NodeInfo.RPCAddress, rec.IP, now.Add(2*time.Minute)); err != nil {
+        t.Fatalf("TouchSuccess: %v", err)
+    }
+
+    addrs, err := store.RecentPeers(now.Add(-time.Hour))
+    if err != nil {
+        t.Fatalf("RecentPeers: %v", err)
+    }
+    if len(addrs) != 1 || addrs[0] != rec.NodeInfo.RPCAddress {
+        t.Fatalf("RecentPeers = %v, want [%s]", addrs, rec.NodeInfo.RPCAddress)
+    }
+}
+
+// TestUpsertPeerConcurrent is a regression test for SQLITE_BUSY errors under
+// concurrent writers, the scenario introduced once worker-pool crawling
+// (chunk0-1) started calling UpsertPeer from up to --workers goroutines at
+// once against the same db file.
+func TestUpsertPeerConcurrent(t *testing.T) {
+    dbPath := filepath.Join(t.TempDir(), "peers.db")
+    store, err := OpenPeerStore(dbPath)
+    if err != nil {
+        t.Fatalf("OpenPeerStore: %v", err)
+    }
+    defer store.Close()
+
+    const n = 50
+    var wg sync.WaitGroup
+    errs := make(chan error, n)
+    for i := 0; i < n; i++ {
+        wg.Add(1)
+        go func(i int) {
+            defer wg.Done()
+            rec := PeerRecord{
+                IP: "1.2.3.4",
+                NodeInfo: PeerNodeInfo{
+                    Moniker:    "node",
+                    Version:    "0.38.0",
+                    RPCAddress: "http://1.2.3.4:26657",
+                },
+            }
+            errs <- store.UpsertPeer(rec, time.Now())
+        }(i)
+    }
+    wg.Wait()
+    close(errs)
+
+    for err := range errs {
+        if err != nil {
+            t.Errorf("concurrent UpsertPeer failed: %v", err)
+        }
+    }
+}
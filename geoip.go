@@ -0,0 +1,112 @@
+package main
+
+import (
+    "fmt"
+    "net"
+    "strconv"
+    "sync"
+
+    "github.com/oschwald/geoip2-golang"
+)
+
+// GeoInfo is the enrichment attached to a peer once a --geoip-db lookup
+// succeeds. Fields are left zero-valued when no match is found.
+type GeoInfo struct {
+    Country   string  `json:"country,omitempty"`
+    City      string  `json:"city,omitempty"`
+    Latitude  float64 `json:"latitude,omitempty"`
+    Longitude float64 `json:"longitude,omitempty"`
+    ASN       uint    `json:"asn,omitempty"`
+    ASOrg     string  `json:"as_org,omitempty"`
+}
+
+func (g GeoInfo) csvFields() []string {
+    return []string{
+        g.Country,
+        g.City,
+        strconv.FormatFloat(g.Latitude, 'f', -1, 64),
+        strconv.FormatFloat(g.Longitude, 'f', -1, 64),
+        strconv.FormatUint(uint64(g.ASN), 10),
+        g.ASOrg,
+    }
+}
+
+// geoIPLookup resolves peer IPs against local MaxMind GeoLite2 databases,
+// caching results in memory since the same IP can show up as a peer of many
+// different nodes during a single crawl. City and ASN data ship as separate
+// .mmdb files, so asnDB is a distinct optional Reader rather than reusing db.
+type geoIPLookup struct {
+    db    *geoip2.Reader
+    asnDB *geoip2.Reader
+
+    mu    sync.Mutex
+    cache map[string]GeoInfo
+}
+
+// newGeoIPLookup opens the City database at cityPath, and, if asnPath is
+// non-empty, the separate ASN database needed to populate asn/as_org.
+func newGeoIPLookup(cityPath, asnPath string) (*geoIPLookup, error) {
+    db, err := geoip2.Open(cityPath)
+    if err != nil {
+        return nil, fmt.Errorf("opening geoip city database %s: %w", cityPath, err)
+    }
+
+    var asnDB *geoip2.Reader
+    if asnPath != "" {
+        asnDB, err = geoip2.Open(asnPath)
+        if err != nil {
+            db.Close()
+            return nil, fmt.Errorf("opening geoip asn database %s: %w", asnPath, err)
+        }
+    }
+
+    return &geoIPLookup{db: db, asnDB: asnDB, cache: make(map[string]GeoInfo)}, nil
+}
+
+func (g *geoIPLookup) lookup(ip string) (GeoInfo, error) {
+    g.mu.Lock()
+    if info, ok := g.cache[ip]; ok {
+        g.mu.Unlock()
+        return info, nil
+    }
+    g.mu.Unlock()
+
+    parsed := net.ParseIP(ip)
+    if parsed == nil {
+        return GeoInfo{}, fmt.Errorf("invalid IP address %q", ip)
+    }
+
+    city, err := g.db.City(parsed)
+    if err != nil {
+        return GeoInfo{}, fmt.Errorf("city lookup for %s: %w", ip, err)
+    }
+
+    info := GeoInfo{
+        Country:   city.Country.IsoCode,
+        City:      city.City.Names["en"],
+        Latitude:  city.Location.Latitude,
+        Longitude: city.Location.Longitude,
+    }
+
+    // The ASN/AS-org fields live in a separate GeoLite2-ASN database and are
+    // only populated when --geoip-asn-db was given; with a City database
+    // alone, country/city are filled in and asn/as_org stay empty.
+    if g.asnDB != nil {
+        if asn, err := g.asnDB.ASN(parsed); err == nil {
+            info.ASN = asn.AutonomousSystemNumber
+            info.ASOrg = asn.AutonomousSystemOrganization
+        }
+    }
+
+    g.mu.Lock()
+    g.cache[ip] = info
+    g.mu.Unlock()
+    return info, nil
+}
+
+func (g *geoIPLookup) Close() error {
+    if g.asnDB != nil {
+        g.asnDB.Close()
+    }
+    return g.db.Close()
+}
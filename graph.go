@@ -0,0 +1,138 @@
+package main
+
+import (
+    "fmt"
+    "os"
+    "sort"
+    "strings"
+    "sync"
+)
+
+// graphNode carries the attributes worth plotting for a node in the crawl
+// graph. It's intentionally a subset of PeerRecord: just enough for
+// visualization and clustering analysis, not the full identity/health record.
+type graphNode struct {
+    Moniker string
+    Version string
+    Country string
+}
+
+// Graph is the in-memory adjacency structure the crawler builds alongside
+// seen: a directed edge from A to B means "A's /net_info reported B as a
+// peer", which the flat CSV/JSON output throws away entirely today.
+type Graph struct {
+    mu    sync.Mutex
+    nodes map[string]graphNode
+    edges map[[2]string]struct{}
+}
+
+func NewGraph() *Graph {
+    return &Graph{
+        nodes: make(map[string]graphNode),
+        edges: make(map[[2]string]struct{}),
+    }
+}
+
+// AddEdge records that `from` reported `to` as a peer, creating bare node
+// entries for either side if this is the first time either has been seen.
+func (g *Graph) AddEdge(from, to string) {
+    g.mu.Lock()
+    defer g.mu.Unlock()
+    if _, ok := g.nodes[from]; !ok {
+        g.nodes[from] = graphNode{}
+    }
+    if _, ok := g.nodes[to]; !ok {
+        g.nodes[to] = graphNode{}
+    }
+    g.edges[[2]string{from, to}] = struct{}{}
+}
+
+// SetNodeAttrs fills in the moniker/version/country for a node once they're
+// known, overwriting any bare placeholder entry AddEdge may have created.
+func (g *Graph) SetNodeAttrs(id string, attrs graphNode) {
+    g.mu.Lock()
+    defer g.mu.Unlock()
+    g.nodes[id] = attrs
+}
+
+func (g *Graph) snapshot() (ids []string, nodes map[string]graphNode, edges [][2]string) {
+    g.mu.Lock()
+    defer g.mu.Unlock()
+
+    nodes = make(map[string]graphNode, len(g.nodes))
+    for id, n := range g.nodes {
+        ids = append(ids, id)
+        nodes[id] = n
+    }
+    sort.Strings(ids)
+
+    for e := range g.edges {
+        edges = append(edges, e)
+    }
+    sort.Slice(edges, func(i, j int) bool {
+        if edges[i][0] != edges[j][0] {
+            return edges[i][0] < edges[j][0]
+        }
+        return edges[i][1] < edges[j][1]
+    })
+    return ids, nodes, edges
+}
+
+func dotEscape(s string) string {
+    return strings.ReplaceAll(s, `"`, `\"`)
+}
+
+// WriteDOT renders the graph in Graphviz DOT format.
+func (g *Graph) WriteDOT(path string) error {
+    ids, nodes, edges := g.snapshot()
+
+    var b strings.Builder
+    b.WriteString("digraph crawl {\n")
+    for _, id := range ids {
+        n := nodes[id]
+        fmt.Fprintf(&b, "  %q [moniker=%q, version=%q, country=%q];\n",
+            id, dotEscape(n.Moniker), dotEscape(n.Version), dotEscape(n.Country))
+    }
+    for _, e := range edges {
+        fmt.Fprintf(&b, "  %q -> %q;\n", e[0], e[1])
+    }
+    b.WriteString("}\n")
+
+    return os.WriteFile(path, []byte(b.String()), 0644)
+}
+
+func xmlEscape(s string) string {
+    r := strings.NewReplacer(`&`, "&amp;", `<`, "&lt;", `>`, "&gt;", `"`, "&quot;")
+    return r.Replace(s)
+}
+
+// WriteGraphML renders the graph in GraphML, with moniker/version/country
+// declared as keys on the node elements.
+func (g *Graph) WriteGraphML(path string) error {
+    ids, nodes, edges := g.snapshot()
+
+    var b strings.Builder
+    b.WriteString(`<?xml version="1.0" encoding="UTF-8"?>` + "\n")
+    b.WriteString(`<graphml xmlns="http://graphml.graphdrawing.org/xmlns">` + "\n")
+    b.WriteString(`  <key id="moniker" for="node" attr.name="moniker" attr.type="string"/>` + "\n")
+    b.WriteString(`  <key id="version" for="node" attr.name="version" attr.type="string"/>` + "\n")
+    b.WriteString(`  <key id="country" for="node" attr.name="country" attr.type="string"/>` + "\n")
+    b.WriteString(`  <graph id="crawl" edgedefault="directed">` + "\n")
+
+    for _, id := range ids {
+        n := nodes[id]
+        fmt.Fprintf(&b, "    <node id=%q>\n", xmlEscape(id))
+        fmt.Fprintf(&b, "      <data key=\"moniker\">%s</data>\n", xmlEscape(n.Moniker))
+        fmt.Fprintf(&b, "      <data key=\"version\">%s</data>\n", xmlEscape(n.Version))
+        fmt.Fprintf(&b, "      <data key=\"country\">%s</data>\n", xmlEscape(n.Country))
+        b.WriteString("    </node>\n")
+    }
+    for i, e := range edges {
+        fmt.Fprintf(&b, "    <edge id=\"e%d\" source=%q target=%q/>\n", i, xmlEscape(e[0]), xmlEscape(e[1]))
+    }
+
+    b.WriteString("  </graph>\n")
+    b.WriteString("</graphml>\n")
+
+    return os.WriteFile(path, []byte(b.String()), 0644)
+}
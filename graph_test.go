@@ -0,0 +1,52 @@
+package main
+
+import (
+    "os"
+    "path/filepath"
+    "strings"
+    "testing"
+)
+
+func TestWriteDOTEscapesQuotesAndRendersEdges(t *testing.T) {
+    g := NewGraph()
+    g.AddEdge("http://a:26657", "http://b:26657")
+    g.SetNodeAttrs("http://a:26657", graphNode{Moniker: `quote"node`, Version: "1.0", Country: "US"})
+
+    path := filepath.Join(t.TempDir(), "graph.dot")
+    if err := g.WriteDOT(path); err != nil {
+        t.Fatalf("WriteDOT: %v", err)
+    }
+    out, err := os.ReadFile(path)
+    if err != nil {
+        t.Fatalf("reading dot output: %v", err)
+    }
+
+    if !strings.Contains(string(out), `quote\\\"node`) {
+        t.Errorf("expected escaped moniker in DOT output, got: %s", out)
+    }
+    if !strings.Contains(string(out), `"http://a:26657" -> "http://b:26657"`) {
+        t.Errorf("expected edge in DOT output, got: %s", out)
+    }
+}
+
+func TestWriteGraphMLEscapesXML(t *testing.T) {
+    g := NewGraph()
+    g.AddEdge("a", "b")
+    g.SetNodeAttrs("a", graphNode{Moniker: `<tag & "quote">`})
+
+    path := filepath.Join(t.TempDir(), "graph.graphml")
+    if err := g.WriteGraphML(path); err != nil {
+        t.Fatalf("WriteGraphML: %v", err)
+    }
+    out, err := os.ReadFile(path)
+    if err != nil {
+        t.Fatalf("reading graphml output: %v", err)
+    }
+
+    if strings.Contains(string(out), "<tag") {
+        t.Errorf("expected moniker to be XML-escaped, got: %s", out)
+    }
+    if !strings.Contains(string(out), "&lt;tag &amp; &quot;quote&quot;&gt;") {
+        t.Errorf("expected escaped moniker text in GraphML output, got: %s", out)
+    }
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+    "fmt"
+    "io"
+    "net/http"
+    "sort"
+    "strings"
+    "sync"
+)
+
+// Metrics is a minimal Prometheus text-exposition registry for the crawler —
+// enough to expose peer counts and query health as a scrape target without
+// pulling in the full client library for one gauge and two counters.
+type Metrics struct {
+    mu            sync.Mutex
+    peers         map[[3]string]int // network, version, moniker -> count
+    queryFailures uint64
+    queryTimeouts uint64
+}
+
+func NewMetrics() *Metrics {
+    return &Metrics{peers: make(map[[3]string]int)}
+}
+
+func (m *Metrics) RecordPeer(network, version, moniker string) {
+    m.mu.Lock()
+    defer m.mu.Unlock()
+    m.peers[[3]string{network, version, moniker}]++
+}
+
+func (m *Metrics) IncQueryFailure() {
+    m.mu.Lock()
+    defer m.mu.Unlock()
+    m.queryFailures++
+}
+
+func (m *Metrics) IncQueryTimeout() {
+    m.mu.Lock()
+    defer m.mu.Unlock()
+    m.queryTimeouts++
+}
+
+func (m *Metrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+    m.mu.Lock()
+    defer m.mu.Unlock()
+
+    var b strings.Builder
+    b.WriteString("# HELP cometbft_crawler_peers_total Number of peers discovered, by network/version/moniker.\n")
+    b.WriteString("# TYPE cometbft_crawler_peers_total gauge\n")
+
+    keys := make([][3]string, 0, len(m.peers))
+    for k := range m.peers {
+        keys = append(keys, k)
+    }
+    sort.Slice(keys, func(i, j int) bool {
+        if keys[i][0] != keys[j][0] {
+            return keys[i][0] < keys[j][0]
+        }
+        if keys[i][1] != keys[j][1] {
+            return keys[i][1] < keys[j][1]
+        }
+        return keys[i][2] < keys[j][2]
+    })
+    for _, k := range keys {
+        fmt.Fprintf(&b, "cometbft_crawler_peers_total{network=%q,version=%q,moniker=%q} %d\n", k[0], k[1], k[2], m.peers[k])
+    }
+
+    b.WriteString("# HELP cometbft_crawler_query_failures_total Number of failed /status or /net_info queries.\n")
+    b.WriteString("# TYPE cometbft_crawler_query_failures_total counter\n")
+    fmt.Fprintf(&b, "cometbft_crawler_query_failures_total %d\n", m.queryFailures)
+
+    b.WriteString("# HELP cometbft_crawler_query_timeouts_total Number of /status or /net_info queries that timed out.\n")
+    b.WriteString("# TYPE cometbft_crawler_query_timeouts_total counter\n")
+    fmt.Fprintf(&b, "cometbft_crawler_query_timeouts_total %d\n", m.queryTimeouts)
+
+    io.WriteString(w, b.String())
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+    "net/http/httptest"
+    "strings"
+    "testing"
+)
+
+func TestMetricsServeHTTPExposesPeersAndCounters(t *testing.T) {
+    m := NewMetrics()
+    m.RecordPeer("test-net", "0.38.0", "node-a")
+    m.RecordPeer("test-net", "0.38.0", "node-a")
+    m.RecordPeer("test-net", "0.38.1", "node-b")
+    m.IncQueryFailure()
+    m.IncQueryTimeout()
+    m.IncQueryTimeout()
+
+    rec := httptest.NewRecorder()
+    m.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+    body := rec.Body.String()
+
+    wantLines := []string{
+        `cometbft_crawler_peers_total{network="test-net",version="0.38.0",moniker="node-a"} 2`,
+        `cometbft_crawler_peers_total{network="test-net",version="0.38.1",moniker="node-b"} 1`,
+        `cometbft_crawler_query_failures_total 1`,
+        `cometbft_crawler_query_timeouts_total 2`,
+    }
+    for _, want := range wantLines {
+        if !strings.Contains(body, want) {
+            t.Errorf("response body missing line %q, got:\n%s", want, body)
+        }
+    }
+}
+
+func TestMetricsServeHTTPOrdersPeerSeriesDeterministically(t *testing.T) {
+    m := NewMetrics()
+    m.RecordPeer("net-b", "v1", "z")
+    m.RecordPeer("net-a", "v2", "a")
+    m.RecordPeer("net-a", "v1", "a")
+
+    rec := httptest.NewRecorder()
+    m.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+    body := rec.Body.String()
+
+    first := strings.Index(body, `network="net-a",version="v1"`)
+    second := strings.Index(body, `network="net-a",version="v2"`)
+    third := strings.Index(body, `network="net-b",version="v1"`)
+    if first == -1 || second == -1 || third == -1 {
+        t.Fatalf("expected all three peer series present, got:\n%s", body)
+    }
+    if !(first < second && second < third) {
+        t.Errorf("expected peer series sorted by network then version, got order in body:\n%s", body)
+    }
+}
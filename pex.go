@@ -0,0 +1,191 @@
+package main
+
+import (
+    "context"
+    "fmt"
+    "net"
+    "time"
+
+    "github.com/cometbft/cometbft/crypto"
+    cmtp2p "github.com/cometbft/cometbft/p2p"
+    cmtconn "github.com/cometbft/cometbft/p2p/conn"
+    "github.com/cometbft/cometbft/p2p/pex"
+    tmp2p "github.com/cometbft/cometbft/proto/tendermint/p2p"
+    "github.com/cometbft/cometbft/version"
+
+    "github.com/cometbft/cometbft/libs/protoio"
+)
+
+// PEXClient speaks CometBFT's real p2p handshake and PEX reactor protocol —
+// the same NodeInfo exchange and protobuf PacketMsg framing a real node's
+// switch uses — so it can read a live validator's address book even when
+// its RPC port is firewalled. Its node key is ephemeral: the crawler only
+// reads, so it never needs to be recognized across runs.
+type PEXClient struct {
+    nodeKey crypto.PrivKey
+    network string
+}
+
+func NewPEXClient(network string) *PEXClient {
+    return &PEXClient{nodeKey: generateNodeKey(), network: network}
+}
+
+// FetchAddrs dials p2pAddr (host:port), completes the secret connection and
+// NodeInfo handshakes, and requests the peer's address book over the PEX
+// channel, returning each advertised peer as a "host:port" p2p address.
+func (c *PEXClient) FetchAddrs(ctx context.Context, p2pAddr string) ([]string, error) {
+    type result struct {
+        addrs []string
+        err   error
+    }
+    done := make(chan result, 1)
+
+    go func() {
+        addrs, err := c.fetchAddrs(p2pAddr)
+        done <- result{addrs, err}
+    }()
+
+    select {
+    case <-ctx.Done():
+        return nil, ctx.Err()
+    case r := <-done:
+        return r.addrs, r.err
+    }
+}
+
+func (c *PEXClient) fetchAddrs(p2pAddr string) ([]string, error) {
+    sc, err := dialSecretConnection(p2pAddr, c.nodeKey)
+    if err != nil {
+        return nil, fmt.Errorf("secret connection handshake with %s: %w", p2pAddr, err)
+    }
+    defer sc.Close()
+
+    if err := c.nodeInfoHandshake(sc); err != nil {
+        return nil, fmt.Errorf("node info handshake with %s: %w", p2pAddr, err)
+    }
+
+    w := protoio.NewDelimitedWriter(sc)
+    if err := sendPacketMsg(w, pex.PexChannel, &tmp2p.Message{Sum: &tmp2p.Message_PexRequest{PexRequest: &tmp2p.PexRequest{}}}); err != nil {
+        return nil, fmt.Errorf("sending pexRequestMessage to %s: %w", p2pAddr, err)
+    }
+
+    msg, err := recvReactorMessage(sc)
+    if err != nil {
+        return nil, fmt.Errorf("reading pexAddrsMessage from %s: %w", p2pAddr, err)
+    }
+    addrsMsg, ok := msg.Sum.(*tmp2p.Message_PexAddrs)
+    if !ok {
+        return nil, fmt.Errorf("unexpected PEX message %T from %s, want PexAddrs", msg.Sum, p2pAddr)
+    }
+
+    return netAddrsToHostPorts(addrsMsg.PexAddrs.Addrs), nil
+}
+
+// netAddrsToHostPorts renders a PEX reactor's advertised NetAddresses as
+// "host:port" p2p addresses, discarding the node ID since the crawler only
+// dials by address.
+func netAddrsToHostPorts(addrs []tmp2p.NetAddress) []string {
+    hostPorts := make([]string, 0, len(addrs))
+    for _, a := range addrs {
+        hostPorts = append(hostPorts, net.JoinHostPort(a.IP, fmt.Sprint(a.Port)))
+    }
+    return hostPorts
+}
+
+// nodeInfoHandshake exchanges DefaultNodeInfo messages the same way
+// CometBFT's transport does: a delimited-protobuf write/read race on the
+// already-encrypted secret connection. We only need the PEX channel to be
+// in our advertised channel list for the remote reactor to answer us.
+func (c *PEXClient) nodeInfoHandshake(sc *cmtconn.SecretConnection) error {
+    ours := cmtp2p.DefaultNodeInfo{
+        ProtocolVersion: cmtp2p.NewProtocolVersion(version.P2PProtocol, version.BlockProtocol, 0),
+        DefaultNodeID:   cmtp2p.PubKeyToID(c.nodeKey.PubKey()),
+        ListenAddr:      "0.0.0.0:26656",
+        Network:         c.network,
+        Version:         version.TMCoreSemVer,
+        Channels:        []byte{pex.PexChannel},
+        Moniker:         "cometbft-crawler",
+        Other: cmtp2p.DefaultNodeInfoOther{
+            TxIndex:    "off",
+            RPCAddress: "",
+        },
+    }
+
+    errc := make(chan error, 2)
+    go func() {
+        _, err := protoio.NewDelimitedWriter(sc).WriteMsg(ours.ToProto())
+        errc <- err
+    }()
+
+    var pbPeer tmp2p.DefaultNodeInfo
+    go func() {
+        reader := protoio.NewDelimitedReader(sc, cmtp2p.MaxNodeInfoSize())
+        _, err := reader.ReadMsg(&pbPeer)
+        errc <- err
+    }()
+
+    for i := 0; i < cap(errc); i++ {
+        if err := <-errc; err != nil {
+            return err
+        }
+    }
+
+    if _, err := cmtp2p.DefaultNodeInfoFromToProto(&pbPeer); err != nil {
+        return fmt.Errorf("decoding peer node info: %w", err)
+    }
+    return nil
+}
+
+func sendPacketMsg(w protoio.Writer, channelID byte, msg *tmp2p.Message) error {
+    data, err := msg.Marshal()
+    if err != nil {
+        return err
+    }
+    packet := &tmp2p.Packet{
+        Sum: &tmp2p.Packet_PacketMsg{
+            PacketMsg: &tmp2p.PacketMsg{
+                ChannelID: int32(channelID),
+                EOF:       true,
+                Data:      data,
+            },
+        },
+    }
+    _, err = w.WriteMsg(packet)
+    return err
+}
+
+// recvReactorMessage reads Packet frames until a PacketMsg arrives on the
+// PEX channel, skipping ping/pong keepalives and other channels' traffic a
+// real node may send, then decodes that one frame's payload as a PEX
+// reactor Message.
+//
+// Long PEX responses that CometBFT's MConnection would split across
+// multiple PacketMsg frames on the same channel aren't reassembled here;
+// address books small enough for one frame (the common case) round-trip
+// fine, but for very large ones only the first frame is decoded, which
+// either fails or yields a truncated address list.
+func recvReactorMessage(conn net.Conn) (*tmp2p.Message, error) {
+    if err := conn.SetReadDeadline(time.Now().Add(10 * time.Second)); err != nil {
+        return nil, err
+    }
+    reader := protoio.NewDelimitedReader(conn, 64*1024)
+
+    for {
+        var packet tmp2p.Packet
+        if _, err := reader.ReadMsg(&packet); err != nil {
+            return nil, err
+        }
+        pm, ok := packet.Sum.(*tmp2p.Packet_PacketMsg)
+        if !ok {
+            continue // PacketPing/PacketPong keepalive, not a reactor message
+        }
+        if byte(pm.PacketMsg.ChannelID) != pex.PexChannel {
+            continue
+        }
+        var msg tmp2p.Message
+        if err := msg.Unmarshal(pm.PacketMsg.Data); err != nil {
+            return nil, fmt.Errorf("decoding PEX message: %w", err)
+        }
+        return &msg, nil
+    }
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+    "net"
+    "testing"
+    "time"
+
+    "github.com/cometbft/cometbft/p2p/pex"
+    tmp2p "github.com/cometbft/cometbft/proto/tendermint/p2p"
+
+    "github.com/cometbft/cometbft/libs/protoio"
+)
+
+// TestRecvReactorMessageSkipsKeepalivesAndWrongChannel is a regression test
+// for recvReactorMessage's packet loop: a real node's MConnection can
+// interleave PacketPing/PacketPong keepalives and traffic from other
+// channels with the PEX response, and none of that should be mistaken for
+// the reactor message we're waiting for.
+func TestRecvReactorMessageSkipsKeepalivesAndWrongChannel(t *testing.T) {
+    client, server := net.Pipe()
+    defer client.Close()
+    defer server.Close()
+
+    want := &tmp2p.Message{Sum: &tmp2p.Message_PexAddrs{PexAddrs: &tmp2p.PexAddrs{
+        Addrs: []tmp2p.NetAddress{{ID: "abc", IP: "1.2.3.4", Port: 26656}},
+    }}}
+
+    go func() {
+        w := protoio.NewDelimitedWriter(client)
+        w.WriteMsg(&tmp2p.Packet{Sum: &tmp2p.Packet_PacketPing{PacketPing: &tmp2p.PacketPing{}}})
+        sendPacketMsg(w, 0x42, &tmp2p.Message{Sum: &tmp2p.Message_PexRequest{PexRequest: &tmp2p.PexRequest{}}})
+        w.WriteMsg(&tmp2p.Packet{Sum: &tmp2p.Packet_PacketPong{PacketPong: &tmp2p.PacketPong{}}})
+        sendPacketMsg(w, pex.PexChannel, want)
+    }()
+
+    got, err := recvReactorMessage(server)
+    if err != nil {
+        t.Fatalf("recvReactorMessage: %v", err)
+    }
+    addrs, ok := got.Sum.(*tmp2p.Message_PexAddrs)
+    if !ok {
+        t.Fatalf("got %T, want PexAddrs", got.Sum)
+    }
+    if len(addrs.PexAddrs.Addrs) != 1 || addrs.PexAddrs.Addrs[0].IP != "1.2.3.4" {
+        t.Errorf("got addrs %v, want one addr 1.2.3.4", addrs.PexAddrs.Addrs)
+    }
+}
+
+func TestRecvReactorMessageReturnsErrorOnClosedConn(t *testing.T) {
+    client, server := net.Pipe()
+    defer server.Close()
+    client.Close() // closed before sending anything: reads must fail, not hang
+
+    errc := make(chan error, 1)
+    go func() {
+        _, err := recvReactorMessage(server)
+        errc <- err
+    }()
+
+    select {
+    case err := <-errc:
+        if err == nil {
+            t.Fatalf("expected an error when the peer closes without sending, got nil")
+        }
+    case <-time.After(time.Second):
+        t.Fatalf("recvReactorMessage did not return after the peer closed the connection")
+    }
+}
+
+func TestNetAddrsToHostPorts(t *testing.T) {
+    addrs := []tmp2p.NetAddress{
+        {ID: "abc", IP: "1.2.3.4", Port: 26656},
+        {ID: "def", IP: "::1", Port: 26657},
+    }
+    want := []string{"1.2.3.4:26656", "[::1]:26657"}
+
+    got := netAddrsToHostPorts(addrs)
+    if len(got) != len(want) {
+        t.Fatalf("netAddrsToHostPorts = %v, want %v", got, want)
+    }
+    for i := range want {
+        if got[i] != want[i] {
+            t.Errorf("netAddrsToHostPorts[%d] = %q, want %q", i, got[i], want[i])
+        }
+    }
+}
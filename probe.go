@@ -0,0 +1,111 @@
+package main
+
+import (
+    "context"
+    "encoding/json"
+    "io"
+    "net/http"
+    "strconv"
+    "sync/atomic"
+    "time"
+)
+
+// HealthProbe captures the liveness signals for a single peer, collected as
+// a probe stage between discovery (finding the peer in a net_info response)
+// and output, rather than trusting the identity fields alone.
+type HealthProbe struct {
+    LatencyMs         []float64 `json:"latency_ms,omitempty"`
+    LatestBlockHeight int64     `json:"latest_block_height,omitempty"`
+    CatchingUp        bool      `json:"catching_up,omitempty"`
+    VotingPower       int64     `json:"voting_power,omitempty"`
+    Healthy           bool      `json:"healthy"`
+    Stale             bool      `json:"stale,omitempty"`
+}
+
+func (h HealthProbe) csvFields() []string {
+    avgLatency := ""
+    if len(h.LatencyMs) > 0 {
+        var sum float64
+        for _, l := range h.LatencyMs {
+            sum += l
+        }
+        avgLatency = strconv.FormatFloat(sum/float64(len(h.LatencyMs)), 'f', 2, 64)
+    }
+    return []string{
+        avgLatency,
+        strconv.FormatInt(h.LatestBlockHeight, 10),
+        strconv.FormatBool(h.CatchingUp),
+        strconv.FormatInt(h.VotingPower, 10),
+        strconv.FormatBool(h.Healthy),
+        strconv.FormatBool(h.Stale),
+    }
+}
+
+// probeNode queries a peer's own /status for height/voting-power/catching-up
+// and samples /health latency directly, independent of whatever another
+// node's net_info said about it.
+func (c *crawler) probeNode(ctx context.Context, url string, samples int) HealthProbe {
+    var probe HealthProbe
+
+    if body, err := queryNode(ctx, url, "/status"); err == nil {
+        var status StatusResponse
+        if json.Unmarshal(body, &status) == nil {
+            probe.LatestBlockHeight, _ = strconv.ParseInt(status.Result.SyncInfo.LatestBlockHeight, 10, 64)
+            probe.CatchingUp = status.Result.SyncInfo.CatchingUp
+            probe.VotingPower, _ = strconv.ParseInt(status.Result.ValidatorInfo.VotingPower, 10, 64)
+        }
+    }
+
+    allHealthy := samples > 0
+    for i := 0; i < samples; i++ {
+        if err := c.limiters.forHost(url).wait(ctx); err != nil {
+            break
+        }
+        latency, ok, err := probeHealthOnce(ctx, url)
+        if err != nil {
+            allHealthy = false
+            continue
+        }
+        probe.LatencyMs = append(probe.LatencyMs, float64(latency.Microseconds())/1000.0)
+        allHealthy = allHealthy && ok
+    }
+    probe.Healthy = allHealthy
+
+    if probe.LatestBlockHeight > 0 {
+        c.updateMaxHeight(probe.LatestBlockHeight)
+    }
+
+    return probe
+}
+
+func probeHealthOnce(ctx context.Context, url string) (time.Duration, bool, error) {
+    req, err := http.NewRequestWithContext(ctx, http.MethodGet, url+"/health", nil)
+    if err != nil {
+        return 0, false, err
+    }
+    start := time.Now()
+    resp, err := client.Do(req)
+    if err != nil {
+        return time.Since(start), false, err
+    }
+    defer resp.Body.Close()
+    io.Copy(io.Discard, resp.Body)
+    return time.Since(start), resp.StatusCode == http.StatusOK, nil
+}
+
+// updateMaxHeight records the highest block height seen so far across the
+// crawl. This running max is only a provisional value during the crawl;
+// staleness is judged against the final max once the crawl completes (see
+// crawler.finalize), not against whatever this happens to be at the moment
+// a given peer is probed.
+func (c *crawler) updateMaxHeight(height int64) {
+    for {
+        cur := atomic.LoadInt64(&c.maxHeight)
+        if height <= cur {
+            return
+        }
+        if atomic.CompareAndSwapInt64(&c.maxHeight, cur, height) {
+            return
+        }
+    }
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+    "path/filepath"
+    "testing"
+)
+
+// TestFinalizeRecomputesStaleAgainstFinalMaxHeight is a regression test for
+// staleness being judged against the running max height observed so far
+// (order-dependent) instead of the crawl's final max height. A peer probed
+// early, before the network's tip height was seen, must still end up
+// flagged stale once the later, higher height is known.
+func TestFinalizeRecomputesStaleAgainstFinalMaxHeight(t *testing.T) {
+    sink := newJSONSink(filepath.Join(t.TempDir(), "out.json"))
+    c := newCrawler("test-net", 0, 1000, 4096, sink, nil, nil, nil, nil, 1, 10, nil)
+
+    early := PeerRecord{IP: "1.1.1.1", Health: HealthProbe{LatestBlockHeight: 100}}
+    c.updateMaxHeight(100)
+    c.record(early) // buffered: not yet written, Stale not yet known
+
+    c.updateMaxHeight(1000) // a later peer reports the network's true tip
+
+    c.finalize()
+
+    if len(sink.records) != 1 {
+        t.Fatalf("expected 1 finalized record, got %d", len(sink.records))
+    }
+    if !sink.records[0].Health.Stale {
+        t.Errorf("expected early peer to be flagged stale against the final max height, got Stale=false")
+    }
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+    "fmt"
+    "net"
+
+    "github.com/cometbft/cometbft/crypto"
+    "github.com/cometbft/cometbft/crypto/ed25519"
+    cmtconn "github.com/cometbft/cometbft/p2p/conn"
+)
+
+// generateNodeKey creates an ephemeral Ed25519 node key for a single crawl.
+// Unlike a long-lived validator, the crawler never needs to be recognized
+// again, so there's nothing to persist.
+func generateNodeKey() crypto.PrivKey {
+    return ed25519.GenPrivKey()
+}
+
+// dialSecretConnection opens a TCP connection to addr and performs
+// CometBFT's real secret-connection handshake via p2p/conn.MakeSecretConnection,
+// so the result actually interoperates with a live CometBFT node's p2p
+// listener instead of a bespoke protocol only this crawler could speak.
+func dialSecretConnection(addr string, nodeKey crypto.PrivKey) (*cmtconn.SecretConnection, error) {
+    conn, err := net.Dial("tcp", addr)
+    if err != nil {
+        return nil, fmt.Errorf("dialing %s: %w", addr, err)
+    }
+
+    sc, err := cmtconn.MakeSecretConnection(conn, nodeKey)
+    if err != nil {
+        conn.Close()
+        return nil, fmt.Errorf("secret connection handshake with %s: %w", addr, err)
+    }
+    return sc, nil
+}
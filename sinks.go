@@ -0,0 +1,153 @@
+package main
+
+import (
+    "encoding/csv"
+    "encoding/json"
+    "fmt"
+    "os"
+    "sync"
+)
+
+// PeerRecord is the unit of output the crawler hands to an OutputSink. It
+// mirrors the nested shape of the upstream /net_info response so sinks that
+// can preserve structure (JSON, NDJSON) don't have to flatten it themselves.
+type PeerRecord struct {
+    IP       string       `json:"ip"`
+    Network  string       `json:"network"`
+    NodeInfo PeerNodeInfo `json:"node_info"`
+    Geo      GeoInfo      `json:"geo,omitempty"`
+    Health   HealthProbe  `json:"health,omitempty"`
+}
+
+type PeerNodeInfo struct {
+    Moniker    string `json:"moniker"`
+    Version    string `json:"version"`
+    RPCAddress string `json:"rpc_address"`
+}
+
+// OutputSink receives peer records as they're discovered and finalizes
+// whatever it's writing to on Close. Implementations must be safe for
+// concurrent use, since workers discover peers in parallel.
+type OutputSink interface {
+    Write(PeerRecord) error
+    Close() error
+}
+
+func newOutputSink(format, path string) (OutputSink, error) {
+    switch format {
+    case "csv":
+        return newCSVSink(path)
+    case "json":
+        return newJSONSink(path), nil
+    case "ndjson":
+        return newNDJSONSink(path)
+    default:
+        return nil, fmt.Errorf("unknown output format %q (want csv, json, or ndjson)", format)
+    }
+}
+
+// csvSink writes peers as flat rows. The first three columns (ip, moniker,
+// version) are the tool's original CSV output; rpc_address and the geo and
+// health columns follow them.
+type csvSink struct {
+    mu     sync.Mutex
+    file   *os.File
+    writer *csv.Writer
+}
+
+func newCSVSink(path string) (*csvSink, error) {
+    f, err := os.Create(path)
+    if err != nil {
+        return nil, err
+    }
+    w := csv.NewWriter(f)
+    header := []string{
+        "ip", "moniker", "version", "rpc_address", "country", "city", "latitude", "longitude", "asn", "as_org",
+        "latency_ms", "latest_block_height", "catching_up", "voting_power", "healthy", "stale",
+    }
+    if err := w.Write(header); err != nil {
+        f.Close()
+        return nil, err
+    }
+    return &csvSink{file: f, writer: w}, nil
+}
+
+func (s *csvSink) Write(p PeerRecord) error {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    row := append([]string{p.IP, p.NodeInfo.Moniker, p.NodeInfo.Version, p.NodeInfo.RPCAddress}, p.Geo.csvFields()...)
+    row = append(row, p.Health.csvFields()...)
+    return s.writer.Write(row)
+}
+
+func (s *csvSink) Close() error {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    s.writer.Flush()
+    if err := s.writer.Error(); err != nil {
+        s.file.Close()
+        return err
+    }
+    return s.file.Close()
+}
+
+// jsonSink buffers every peer and writes a single JSON array on Close, since
+// a partially written array isn't valid JSON for downstream consumers.
+type jsonSink struct {
+    mu      sync.Mutex
+    path    string
+    records []PeerRecord
+}
+
+func newJSONSink(path string) *jsonSink {
+    return &jsonSink{path: path}
+}
+
+func (s *jsonSink) Write(p PeerRecord) error {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    s.records = append(s.records, p)
+    return nil
+}
+
+func (s *jsonSink) Close() error {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    f, err := os.Create(s.path)
+    if err != nil {
+        return err
+    }
+    defer f.Close()
+    enc := json.NewEncoder(f)
+    enc.SetIndent("", "  ")
+    return enc.Encode(s.records)
+}
+
+// ndjsonSink writes one JSON object per line as soon as a peer is
+// discovered, so downstream tools can consume results incrementally during
+// long crawls instead of waiting for the whole thing to finish.
+type ndjsonSink struct {
+    mu   sync.Mutex
+    file *os.File
+    enc  *json.Encoder
+}
+
+func newNDJSONSink(path string) (*ndjsonSink, error) {
+    f, err := os.Create(path)
+    if err != nil {
+        return nil, err
+    }
+    return &ndjsonSink{file: f, enc: json.NewEncoder(f)}, nil
+}
+
+func (s *ndjsonSink) Write(p PeerRecord) error {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    return s.enc.Encode(p)
+}
+
+func (s *ndjsonSink) Close() error {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    return s.file.Close()
+}
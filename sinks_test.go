@@ -0,0 +1,121 @@
+package main
+
+import (
+    "encoding/json"
+    "os"
+    "path/filepath"
+    "strings"
+    "testing"
+)
+
+func testRecord() PeerRecord {
+    return PeerRecord{
+        IP:      "1.2.3.4",
+        Network: "test-net",
+        NodeInfo: PeerNodeInfo{
+            Moniker:    "node-a",
+            Version:    "0.38.0",
+            RPCAddress: "http://1.2.3.4:26657",
+        },
+    }
+}
+
+func TestCSVSinkWritesHeaderAndRows(t *testing.T) {
+    path := filepath.Join(t.TempDir(), "out.csv")
+    sink, err := newCSVSink(path)
+    if err != nil {
+        t.Fatalf("newCSVSink: %v", err)
+    }
+    if err := sink.Write(testRecord()); err != nil {
+        t.Fatalf("Write: %v", err)
+    }
+    if err := sink.Close(); err != nil {
+        t.Fatalf("Close: %v", err)
+    }
+
+    data, err := os.ReadFile(path)
+    if err != nil {
+        t.Fatalf("ReadFile: %v", err)
+    }
+    lines := strings.Split(strings.TrimRight(string(data), "\n"), "\n")
+    if len(lines) != 2 {
+        t.Fatalf("got %d lines, want 2 (header + 1 row): %q", len(lines), data)
+    }
+    if !strings.HasPrefix(lines[0], "ip,moniker,version,rpc_address,") {
+        t.Errorf("unexpected header: %q", lines[0])
+    }
+    if !strings.HasPrefix(lines[1], "1.2.3.4,node-a,0.38.0,http://1.2.3.4:26657,") {
+        t.Errorf("unexpected row: %q", lines[1])
+    }
+}
+
+func TestJSONSinkBuffersUntilClose(t *testing.T) {
+    path := filepath.Join(t.TempDir(), "out.json")
+    sink := newJSONSink(path)
+
+    if err := sink.Write(testRecord()); err != nil {
+        t.Fatalf("Write: %v", err)
+    }
+    if _, err := os.Stat(path); !os.IsNotExist(err) {
+        t.Fatalf("expected no file on disk before Close, stat err = %v", err)
+    }
+
+    if err := sink.Close(); err != nil {
+        t.Fatalf("Close: %v", err)
+    }
+
+    var got []PeerRecord
+    data, err := os.ReadFile(path)
+    if err != nil {
+        t.Fatalf("ReadFile: %v", err)
+    }
+    if err := json.Unmarshal(data, &got); err != nil {
+        t.Fatalf("Unmarshal: %v", err)
+    }
+    if len(got) != 1 || got[0].IP != "1.2.3.4" {
+        t.Errorf("got %+v, want one record with IP 1.2.3.4", got)
+    }
+}
+
+func TestNDJSONSinkWritesOneObjectPerLineImmediately(t *testing.T) {
+    path := filepath.Join(t.TempDir(), "out.ndjson")
+    sink, err := newNDJSONSink(path)
+    if err != nil {
+        t.Fatalf("newNDJSONSink: %v", err)
+    }
+
+    if err := sink.Write(testRecord()); err != nil {
+        t.Fatalf("Write: %v", err)
+    }
+    if err := sink.Write(testRecord()); err != nil {
+        t.Fatalf("Write: %v", err)
+    }
+
+    data, err := os.ReadFile(path)
+    if err != nil {
+        t.Fatalf("ReadFile before Close: %v", err)
+    }
+    lines := strings.Split(strings.TrimRight(string(data), "\n"), "\n")
+    if len(lines) != 2 {
+        t.Fatalf("expected writes to land before Close, got %d lines: %q", len(lines), data)
+    }
+    for i, line := range lines {
+        var rec PeerRecord
+        if err := json.Unmarshal([]byte(line), &rec); err != nil {
+            t.Fatalf("line %d not valid JSON: %v", i, err)
+        }
+        if rec.IP != "1.2.3.4" {
+            t.Errorf("line %d IP = %q, want 1.2.3.4", i, rec.IP)
+        }
+    }
+
+    if err := sink.Close(); err != nil {
+        t.Fatalf("Close: %v", err)
+    }
+}
+
+func TestNewOutputSinkUnknownFormat(t *testing.T) {
+    if _, err := newOutputSink("yaml", filepath.Join(t.TempDir(), "out.yaml")); err == nil {
+        t.Fatalf("expected an error for an unknown format, got nil")
+    }
+}